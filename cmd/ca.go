@@ -0,0 +1,157 @@
+/*
+Copyright © 2024 Mahmoud Mosua <m.mousa@hey.com>
+
+Licensed under the GNU GPL License, Version 3.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+https://www.gnu.org/licenses/gpl-3.0.en.html
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/mightymoud/sidekick/internal/ca"
+	"github.com/mightymoud/sidekick/internal/config"
+	"github.com/mightymoud/sidekick/utils"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// caCmd groups the commands that manage the internal mTLS CA provisioned
+// during `sidekick init`.
+var caCmd = &cobra.Command{
+	Use:   "ca",
+	Short: "Manage the internal mTLS CA used to secure sidekick/VPS traffic",
+}
+
+var caRotateCmd = &cobra.Command{
+	Use:   "rotate",
+	Short: "Rotate the internal CA and reissue the VPS server and CLI client certs",
+	Run: func(cmd *cobra.Command, args []string) {
+		profile, _ := cmd.Flags().GetString("profile")
+
+		configState, err := config.Load()
+		if err != nil {
+			log.Fatalf("Unable to load sidekick config: %s", err)
+		}
+
+		server := configState.GetServerAddress(profile)
+		if server == "" {
+			log.Fatalf("No server configured for profile '%s' - run 'sidekick init --profile %s' first", profile, profile)
+		}
+
+		sshClient, err := utils.Login(server, "sidekick", "", 22)
+		if err != nil {
+			log.Fatalf("Unable to login to VPS for profile '%s': %s", profile, err)
+		}
+
+		spinner, _ := pterm.DefaultSpinner.Start("Rotating internal CA")
+		if err := provisionCA(cmd, sshClient, configState, profile); err != nil {
+			spinner.Fail("Failed to rotate internal CA")
+			log.Fatal(err)
+		}
+		configState.SetStageComplete(profile, "ca")
+		spinner.Success("Internal CA rotated")
+
+		if err := viper.WriteConfig(); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+var caIssueCmd = &cobra.Command{
+	Use:   "issue <name>",
+	Short: "Issue a new certificate signed by the internal CA",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		profile, _ := cmd.Flags().GetString("profile")
+		name := args[0]
+		years, _ := cmd.Flags().GetInt("years")
+		server, _ := cmd.Flags().GetBool("server")
+
+		configState, err := config.Load()
+		if err != nil {
+			log.Fatalf("Unable to load sidekick config: %s", err)
+		}
+
+		rootCertPEM := configState.GetCARootCert(profile)
+		if rootCertPEM == "" {
+			log.Fatalf("No internal CA provisioned for profile '%s' - run 'sidekick init --profile %s' first", profile, profile)
+		}
+
+		vpsAddress := configState.GetServerAddress(profile)
+		sshClient, err := utils.Login(vpsAddress, "sidekick", "", 22)
+		if err != nil {
+			log.Fatalf("Unable to login to VPS for profile '%s': %s", profile, err)
+		}
+
+		spinner, _ := pterm.DefaultSpinner.Start(fmt.Sprintf("Issuing certificate for %s", name))
+
+		// The CA's private key is never kept on this machine - it only ever
+		// lives encrypted on the VPS, so fetch and decrypt it there with
+		// sops, exactly the way provisionCA wrote it in the first place.
+		ch, err := utils.RunCommand(sshClient, "sops decrypt $HOME/.sidekick/ca/ca.key.enc")
+		if err != nil {
+			spinner.Fail("Failed to fetch the CA private key from the VPS")
+			log.Fatal(err)
+		}
+		keyPEM := strings.TrimSpace(<-ch)
+		if keyPEM == "" {
+			spinner.Fail("Failed to fetch the CA private key from the VPS")
+			log.Fatalf("Got an empty CA private key back from the VPS for profile '%s'", profile)
+		}
+
+		rootCA, err := ca.Load([]byte(rootCertPEM), []byte(keyPEM))
+		if err != nil {
+			spinner.Fail("Failed to load the internal CA")
+			log.Fatal(err)
+		}
+
+		certPEM, certKeyPEM, err := rootCA.IssueCert(name, years, server)
+		if err != nil {
+			spinner.Fail(fmt.Sprintf("Failed to issue a certificate for %s", name))
+			log.Fatal(err)
+		}
+
+		certPath := fmt.Sprintf("%s.crt", name)
+		keyPath := fmt.Sprintf("%s.key", name)
+		if err := os.WriteFile(certPath, certPEM, 0644); err != nil {
+			spinner.Fail("Failed to write the issued certificate")
+			log.Fatal(err)
+		}
+		if err := os.WriteFile(keyPath, certKeyPEM, 0600); err != nil {
+			spinner.Fail("Failed to write the issued certificate's private key")
+			log.Fatal(err)
+		}
+
+		spinner.Success(fmt.Sprintf("Issued certificate for %s -> %s, %s", name, certPath, keyPath))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(caCmd)
+	caCmd.AddCommand(caRotateCmd)
+	caCmd.AddCommand(caIssueCmd)
+
+	caCmd.PersistentFlags().StringP("profile", "p", config.DefaultProfile, "Name of the server profile to operate on")
+
+	caRotateCmd.Flags().Int("ca-bits", 4096, "RSA key size for the internal CA")
+	caRotateCmd.Flags().Bool("ca-ed25519", false, "Generate an Ed25519 internal CA instead of RSA")
+	caRotateCmd.Flags().Int("ca-years", 10, "Validity, in years, for the internal CA root certificate")
+	caRotateCmd.Flags().String("ca-org", "sidekick", "Organization name for the internal CA's subject")
+	caRotateCmd.Flags().String("ca-country", "US", "Country code for the internal CA's subject")
+
+	caIssueCmd.Flags().Int("years", 1, "Validity, in years, for the issued certificate")
+	caIssueCmd.Flags().Bool("server", false, "Issue a server certificate (for TLS server auth) instead of a client certificate")
+}
@@ -20,13 +20,142 @@ import (
 	"os"
 	"strings"
 
+	"github.com/mightymoud/sidekick/internal/ca"
+	"github.com/mightymoud/sidekick/internal/config"
 	"github.com/mightymoud/sidekick/render"
 	"github.com/mightymoud/sidekick/utils"
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/term"
 )
 
+// isNonInteractive reports whether init should skip every interactive
+// prompt: either the caller asked for it explicitly, or stdout isn't a
+// terminal sidekick could prompt on in the first place (CI runners, piped
+// output).
+func isNonInteractive(cmd *cobra.Command) bool {
+	nonInteractive, _ := cmd.Flags().GetBool("non-interactive")
+	if nonInteractive {
+		return true
+	}
+	if os.Getenv("SIDEKICK_NONINTERACTIVE") == "1" {
+		return true
+	}
+	return !term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// provisionCA generates the internal CA, issues a server cert for the VPS
+// and a client cert for this sidekick install, stores the root cert and
+// client identity in the profile, and ships the root and server certs to the
+// VPS, with the CA and server private keys encrypted with its age key, so
+// Traefik's dashboard/API can require mTLS instead of relying on SSH agent
+// forwarding.
+func provisionCA(cmd *cobra.Command, sshClient *ssh.Client, configState *config.ConfigState, profile string) error {
+	bits, _ := cmd.Flags().GetInt("ca-bits")
+	years, _ := cmd.Flags().GetInt("ca-years")
+	org, _ := cmd.Flags().GetString("ca-org")
+	country, _ := cmd.Flags().GetString("ca-country")
+	ed25519, _ := cmd.Flags().GetBool("ca-ed25519")
+
+	rootCA, err := ca.Generate(ca.Options{Bits: bits, Ed25519: ed25519, Years: years, Org: org, Country: country})
+	if err != nil {
+		return fmt.Errorf("unable to generate internal CA: %w", err)
+	}
+
+	serverCertPEM, serverKeyPEM, err := rootCA.IssueCert(configState.GetServerAddress(profile), years, true)
+	if err != nil {
+		return fmt.Errorf("unable to issue VPS server certificate: %w", err)
+	}
+
+	clientCertPEM, clientKeyPEM, err := rootCA.IssueCert("sidekick-cli", years, false)
+	if err != nil {
+		return fmt.Errorf("unable to issue sidekick CLI client certificate: %w", err)
+	}
+
+	configState.SetCARootCert(profile, string(rootCA.CertPEM))
+	configState.SetClientCert(profile, string(clientCertPEM), string(clientKeyPEM))
+
+	publicKey := configState.GetPublicKey(profile)
+	ch, err := utils.RunCommand(sshClient, fmt.Sprintf(
+		`mkdir -p $HOME/.sidekick/ca && \
+cat <<'EOF' > $HOME/.sidekick/ca/ca.crt
+%s
+EOF
+cat <<'EOF' > $HOME/.sidekick/ca/server.crt
+%s
+EOF
+cat <<'EOF' | sops encrypt --age %s /dev/stdin > $HOME/.sidekick/ca/server.key.enc
+%s
+EOF
+cat <<'EOF' | sops encrypt --age %s /dev/stdin > $HOME/.sidekick/ca/ca.key.enc
+%s
+EOF`,
+		rootCA.CertPEM, serverCertPEM, publicKey, serverKeyPEM, publicKey, rootCA.KeyPEM,
+	))
+	if err != nil {
+		return err
+	}
+	<-ch
+	return nil
+}
+
+// dnsProviderEnvVars lists the credential env vars lego (and therefore
+// traefik's ACME DNS-01 resolver) expects for each supported provider.
+var dnsProviderEnvVars = map[string][]string{
+	"cloudflare":   {"CF_API_EMAIL", "CF_API_KEY", "CF_DNS_API_TOKEN"},
+	"route53":      {"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_REGION"},
+	"digitalocean": {"DO_AUTH_TOKEN"},
+	"hetzner":      {"HETZNER_API_KEY"},
+}
+
+// writeDNSCredentials collects the credential env vars for provider from the
+// local environment, encrypts them with the VPS's age public key using sops,
+// and writes the result to the VPS. GetTraefikStage decrypts it on the VPS
+// into $HOME/.sidekick/dns-credentials.env and passes that file to the
+// Traefik container with --env-file.
+func writeDNSCredentials(sshClient *ssh.Client, provider, publicKey string) error {
+	var missing []string
+	var envFile strings.Builder
+	for _, name := range dnsProviderEnvVars[provider] {
+		value := os.Getenv(name)
+		if value == "" {
+			missing = append(missing, name)
+			continue
+		}
+		fmt.Fprintf(&envFile, "%s=%s\n", name, value)
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf(
+			"missing required env var(s) for --dns-provider %s: %s",
+			provider, strings.Join(missing, ", "),
+		)
+	}
+
+	ch, err := utils.RunCommand(sshClient, fmt.Sprintf(
+		"mkdir -p $HOME/.sidekick && cat <<'EOF' | sops encrypt --age %s /dev/stdin > $HOME/.sidekick/dns-credentials.env.enc\n%sEOF",
+		publicKey, envFile.String(),
+	))
+	if err != nil {
+		return err
+	}
+	<-ch
+	return nil
+}
+
+// parseDomains splits a comma/semicolon-separated list of domains into the
+// main domain plus its SANs, the way traefik's own Domains.Set flag does.
+func parseDomains(raw string) []string {
+	domains := strings.FieldsFunc(raw, func(r rune) bool {
+		return r == ',' || r == ';'
+	})
+	for i, domain := range domains {
+		domains[i] = strings.TrimSpace(domain)
+	}
+	return domains
+}
+
 // initCmd represents the init command
 var initCmd = &cobra.Command{
 	Use:   "init",
@@ -38,10 +167,29 @@ var initCmd = &cobra.Command{
 		pterm.DefaultBasicText.Println("Welcome to Sidekick. We need to collect some details from you first")
 
 		render.RenderSidekickBig()
-		server := viper.GetString("serverAddress")
-		certEmail := viper.GetString("certEmail")
 
+		profile, _ := cmd.Flags().GetString("profile")
+
+		configState, configErr := config.Load()
+		if configErr != nil {
+			log.Fatalf("Unable to load sidekick config: %s", configErr)
+		}
+
+		server, _ := cmd.Flags().GetString("server")
 		if server == "" {
+			server = configState.GetServerAddress(profile)
+		}
+		certEmail, _ := cmd.Flags().GetString("email")
+		if certEmail == "" {
+			certEmail = configState.GetCertEmail(profile)
+		}
+
+		nonInteractive := isNonInteractive(cmd)
+
+		if server == "" {
+			if nonInteractive {
+				log.Fatalf("Missing VPS IP address - pass --server or set it via 'sidekick init --profile %s --server <ip>'", profile)
+			}
 			serverTextInput := pterm.DefaultInteractiveTextInput
 			serverTextInput.DefaultText = "Please enter the IPv4 Address of your VPS"
 			server, _ = serverTextInput.Show()
@@ -52,6 +200,9 @@ var initCmd = &cobra.Command{
 		}
 
 		if certEmail == "" {
+			if nonInteractive {
+				log.Fatalf("Missing cert email - pass --email or set it via 'sidekick init --profile %s --email <email>'", profile)
+			}
 			certEmailTextInput := pterm.DefaultInteractiveTextInput
 			certEmailTextInput.DefaultText = "Please enter an email for use with TLS certs"
 			certEmail, _ = certEmailTextInput.Show()
@@ -61,41 +212,106 @@ var initCmd = &cobra.Command{
 			}
 		}
 
-		viper.Set("serverAddress", server)
-		viper.Set("certEmail", certEmail)
+		sshUser, _ := cmd.Flags().GetString("ssh-user")
+		sshKey, _ := cmd.Flags().GetString("ssh-key")
+		sshPort, _ := cmd.Flags().GetInt("ssh-port")
+		bootstrapUser, _ := cmd.Flags().GetString("bootstrap-user")
+		bootstrapKey, _ := cmd.Flags().GetString("bootstrap-key")
+		allowRootLogin, _ := cmd.Flags().GetBool("allow-root-login")
+		if bootstrapUser == "" {
+			bootstrapUser = sshUser
+		}
+		if bootstrapKey == "" {
+			bootstrapKey = sshKey
+		}
+		if bootstrapUser == "" {
+			if !allowRootLogin {
+				log.Fatalf("No --bootstrap-user given - pass one, or pass --allow-root-login to fall back to the 'root' user")
+			}
+			bootstrapUser = "root"
+		}
+		if nonInteractive && bootstrapKey == "" {
+			log.Fatalf("Missing SSH key - pass --ssh-key (or --bootstrap-key) for non-interactive init")
+		}
+
+		dnsProvider, _ := cmd.Flags().GetString("dns-provider")
+		if dnsProvider == "" {
+			dnsProvider = configState.GetDNSProvider(profile)
+		}
+		domains := configState.GetDomains(profile)
+		if rawDomains, _ := cmd.Flags().GetString("domains"); rawDomains != "" {
+			domains = parseDomains(rawDomains)
+		}
+		if dnsProvider != "" && len(domains) == 0 {
+			if nonInteractive {
+				log.Fatalf("--dns-provider was set but --domains is empty - pass at least the main domain")
+			}
+			domainsTextInput := pterm.DefaultInteractiveTextInput
+			domainsTextInput.DefaultText = "Please enter your domain(s), comma-separated (e.g. example.com,*.example.com)"
+			rawDomains, _ := domainsTextInput.Show()
+			domains = parseDomains(rawDomains)
+		}
+
+		configState.SetServerAddress(profile, server)
+		configState.SetCertEmail(profile, certEmail)
+		configState.SetDNSProvider(profile, dnsProvider)
+		configState.SetDomains(profile, domains)
 
 		pterm.Println()
 		pterm.DefaultHeader.WithFullWidth().Println("Sidekick booting up! 🚀")
 		pterm.Println()
 
-		// init login with checking handshake
-		rootSshClient, err := utils.Login(server, "root")
+		// Connect as the bootstrap user (a pre-provisioned sudo user on most
+		// managed VPS images) rather than root - GetUsersetupStage prefixes
+		// its commands with sudo so this works whether bootstrapUser is
+		// "root" or a regular account.
+		usersetupStage := utils.GetUsersetupStage(bootstrapUser)
+		rootSshClient, err := utils.Login(server, bootstrapUser, bootstrapKey, sshPort)
 		if err != nil {
-			log.Fatalf("Unable to login using 'root' user: %s", err)
+			log.Fatalf("Unable to login using '%s' user: %s", bootstrapUser, err)
 			os.Exit(1)
 		}
 
+		if nonInteractive {
+			pterm.DisableStyling()
+		}
+
 		multi := pterm.DefaultMultiPrinter
-		rootLoginSpinner, _ := pterm.DefaultSpinner.Start("Logging in with root")
+		rootLoginSpinner, _ := pterm.DefaultSpinner.Start(fmt.Sprintf("Logging in with %s", bootstrapUser))
 		stage0Spinner, _ := utils.GetSpinner().WithWriter(multi.NewWriter()).Start("Adding user Sidekick")
 		sidekickLoginSpinner, _ := utils.GetSpinner().WithWriter(multi.NewWriter()).Start("Logging into with sidekick user")
 		stage1Spinner, _ := utils.GetSpinner().WithWriter(multi.NewWriter()).Start("Setting up VPS")
 		stage2Spinner, _ := utils.GetSpinner().WithWriter(multi.NewWriter()).Start("Setting up Docker")
 		stage3Spinner, _ := utils.GetSpinner().WithWriter(multi.NewWriter()).Start("Setting up Traefik")
+		stage4Spinner, _ := utils.GetSpinner().WithWriter(multi.NewWriter()).Start("Setting up internal CA")
 		pterm.Println()
 		multi.Start()
 
 		rootLoginSpinner.Success("Logged in successfully!")
 
 		stage0Spinner.Sequence = []string{"▀ ", " ▀", " ▄", "▄ "}
-		if err := utils.RunStage(rootSshClient, utils.UsersetupStage); err != nil {
-			stage0Spinner.Fail(utils.UsersetupStage.SpinnerFailMessage)
-			panic(err)
+		if configState.IsStageComplete(profile, "user-setup") || utils.RemoteStageComplete(rootSshClient, "user-setup") {
+			stage0Spinner.Success("Sidekick user already exists - skipping")
+		} else {
+			if err := utils.RunStage(rootSshClient, usersetupStage); err != nil {
+				stage0Spinner.Fail(usersetupStage.SpinnerFailMessage)
+				panic(err)
+			}
+			if err := utils.RecordRemoteStage(rootSshClient, "user-setup"); err != nil {
+				panic(err)
+			}
+			configState.SetStageComplete(profile, "user-setup")
+			if err := viper.WriteConfig(); err != nil {
+				panic(err)
+			}
+			stage0Spinner.Success(usersetupStage.SpinnerSuccessMessage)
 		}
-		stage0Spinner.Success(utils.UsersetupStage.SpinnerSuccessMessage)
 
 		sidekickLoginSpinner.Sequence = []string{"▀ ", " ▀", " ▄", "▄ "}
-		sidekickSshClient, err := utils.Login(server, "sidekick")
+		// The user-setup stage copied bootstrapUser's authorized_keys onto the new
+		// sidekick account, so sidekick must be reached with the same key
+		// that authenticated as bootstrapUser, not the package default.
+		sidekickSshClient, err := utils.Login(server, "sidekick", bootstrapKey, sshPort)
 		if err != nil {
 			sidekickLoginSpinner.Fail("Something went wrong logging in to your VPS")
 			panic(err)
@@ -103,41 +319,94 @@ var initCmd = &cobra.Command{
 		sidekickLoginSpinner.Success("Logged in successfully with new user!")
 
 		stage1Spinner.Sequence = []string{"▀ ", " ▀", " ▄", "▄ "}
-		if err := utils.RunStage(sidekickSshClient, utils.SetupStage); err != nil {
-			stage1Spinner.Fail(utils.SetupStage.SpinnerFailMessage)
-			panic(err)
-		}
-		ch, sessionErr := utils.RunCommand(sidekickSshClient, "mkdir -p $HOME/.config/sops/age/ && age-keygen -o $HOME/.config/sops/age/keys.txt 2>&1 ")
-		if sessionErr != nil {
-			stage1Spinner.Fail(utils.SetupStage.SpinnerFailMessage)
-			panic(sessionErr)
+		if configState.IsStageComplete(profile, "vps-setup") && configState.GetPublicKey(profile) != "" {
+			stage1Spinner.Success("VPS already set up - skipping")
+		} else {
+			if err := utils.RunStage(sidekickSshClient, utils.SetupStage); err != nil {
+				stage1Spinner.Fail(utils.SetupStage.SpinnerFailMessage)
+				panic(err)
+			}
+			ch, sessionErr := utils.RunCommand(sidekickSshClient, "mkdir -p $HOME/.config/sops/age/ && age-keygen -o $HOME/.config/sops/age/keys.txt 2>&1 ")
+			if sessionErr != nil {
+				stage1Spinner.Fail(utils.SetupStage.SpinnerFailMessage)
+				panic(sessionErr)
+			}
+			select {
+			case output := <-ch:
+				if strings.HasPrefix(output, "Public key") {
+					publicKey := strings.Split(output, " ")[2:3]
+					configState.SetPublicKey(profile, publicKey[0])
+				}
+			}
+			if err := utils.RecordRemoteStage(sidekickSshClient, "vps-setup"); err != nil {
+				panic(err)
+			}
+			configState.SetStageComplete(profile, "vps-setup")
+			if err := viper.WriteConfig(); err != nil {
+				panic(err)
+			}
+			stage1Spinner.Success(utils.SetupStage.SpinnerSuccessMessage)
 		}
-		select {
-		case output := <-ch:
-			if strings.HasPrefix(output, "Public key") {
-				publicKey := strings.Split(output, " ")[2:3]
-				viper.Set("publicKey", publicKey[0])
+
+		stage4Spinner.Sequence = []string{"▀ ", " ▀", " ▄", "▄ "}
+		if configState.IsStageComplete(profile, "ca") || utils.RemoteStageComplete(sidekickSshClient, "ca") {
+			stage4Spinner.Success("Internal CA already provisioned - skipping")
+		} else {
+			if err := provisionCA(cmd, sidekickSshClient, configState, profile); err != nil {
+				stage4Spinner.Fail("Failed to provision internal CA")
+				panic(err)
 			}
+			if err := utils.RecordRemoteStage(sidekickSshClient, "ca"); err != nil {
+				panic(err)
+			}
+			configState.SetStageComplete(profile, "ca")
+			if err := viper.WriteConfig(); err != nil {
+				panic(err)
+			}
+			stage4Spinner.Success("Internal CA provisioned")
 		}
-		stage1Spinner.Success(utils.SetupStage.SpinnerSuccessMessage)
 
 		stage2Spinner.Sequence = []string{"▀ ", " ▀", " ▄", "▄ "}
-		if err := utils.RunStage(sidekickSshClient, utils.DockerStage); err != nil {
-			stage2Spinner.Fail(utils.DockerStage.SpinnerFailMessage)
-			panic(err)
+		if configState.IsStageComplete(profile, "docker") || utils.RemoteStageComplete(sidekickSshClient, "docker") {
+			stage2Spinner.Success("Docker already installed - skipping")
+		} else {
+			if err := utils.RunStage(sidekickSshClient, utils.DockerStage); err != nil {
+				stage2Spinner.Fail(utils.DockerStage.SpinnerFailMessage)
+				panic(err)
+			}
+			if err := utils.RecordRemoteStage(sidekickSshClient, "docker"); err != nil {
+				panic(err)
+			}
+			configState.SetStageComplete(profile, "docker")
+			if err := viper.WriteConfig(); err != nil {
+				panic(err)
+			}
+			stage2Spinner.Success(utils.DockerStage.SpinnerSuccessMessage)
 		}
-		stage2Spinner.Success(utils.DockerStage.SpinnerSuccessMessage)
 
 		stage3Spinner.Sequence = []string{"▀ ", " ▀", " ▄", "▄ "}
-		traefikStage := utils.GetTraefikStage(certEmail)
-		if err := utils.RunStage(sidekickSshClient, traefikStage); err != nil {
-			stage3Spinner.Fail(traefikStage.SpinnerFailMessage)
-			panic(err)
-		}
-		stage3Spinner.Success(traefikStage.SpinnerSuccessMessage)
-
-		if err := viper.WriteConfig(); err != nil {
-			panic(err)
+		if configState.IsStageComplete(profile, "traefik") || utils.RemoteStageComplete(sidekickSshClient, "traefik") {
+			stage3Spinner.Success("Traefik already set up - skipping")
+		} else {
+			if dnsProvider != "" {
+				if err := writeDNSCredentials(sidekickSshClient, dnsProvider, configState.GetPublicKey(profile)); err != nil {
+					stage3Spinner.Fail("Failed to send DNS provider credentials to the VPS")
+					panic(err)
+				}
+			}
+			traefikStage := utils.GetTraefikStage(certEmail, dnsProvider, domains, configState.GetCARootCert(profile) != "")
+			if err := utils.RunStage(sidekickSshClient, traefikStage); err != nil {
+				stage3Spinner.Fail(traefikStage.SpinnerFailMessage)
+				panic(err)
+			}
+			if err := utils.RecordRemoteStage(sidekickSshClient, "traefik"); err != nil {
+				panic(err)
+			}
+			configState.SetStageComplete(profile, "traefik")
+			if err := viper.WriteConfig(); err != nil {
+				panic(err)
+			}
+			stage3Spinner.Success(traefikStage.SpinnerSuccessMessage)
 		}
 
 		multi.Stop()
@@ -148,39 +417,103 @@ var initCmd = &cobra.Command{
 	},
 }
 
+// legacyConfigCandidates returns every path sidekick has ever looked for its
+// config file in, in the order they should be checked. The first one that
+// exists wins so upgrading sidekick never silently drops a user's settings.
+func legacyConfigCandidates(home string) []string {
+	candidates := []string{}
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		candidates = append(candidates, fmt.Sprintf("%s/sidekick/default.yaml", xdg))
+	}
+	candidates = append(candidates,
+		fmt.Sprintf("%s/.config/sidekick/default.yaml", home),
+		fmt.Sprintf("%s/.sidekick.yaml", home),
+		"/etc/sidekick/config.yaml",
+		"./sidekick.yaml",
+	)
+	return candidates
+}
+
 func initConfig() {
 	home, err := os.UserHomeDir()
 	cobra.CheckErr(err)
 
-	configPath := fmt.Sprintf("%s/.config/sidekick", home)
-	configFile := fmt.Sprintf("%s/default.yaml", configPath)
+	canonicalPath := fmt.Sprintf("%s/.config/sidekick", home)
+	canonicalFile := fmt.Sprintf("%s/default.yaml", canonicalPath)
 
-	makeDirErr := os.MkdirAll(configPath, os.ModePerm)
-	if makeDirErr != nil {
-		log.Fatalf("Error creating directory: %v\n", makeDirErr)
-		os.Exit(1)
+	configFile := os.Getenv("SIDEKICK_CONFIG")
+	if configFile == "" {
+		configFile = configFlag
 	}
+	explicitConfig := configFile != ""
 
-	viper.AddConfigPath(configPath)
-	viper.SetConfigType("yaml")
-	viper.SetConfigName("default")
-	file, fileCreateErr := os.Create(configFile)
-	if fileCreateErr != nil {
-		log.Fatalf("Error creating configFile: %v\n", fileCreateErr)
-		os.Exit(1)
+	if configFile == "" {
+		for _, candidate := range legacyConfigCandidates(home) {
+			if _, statErr := os.Stat(candidate); statErr == nil {
+				configFile = candidate
+				break
+			}
+		}
+	}
+
+	if makeDirErr := os.MkdirAll(canonicalPath, os.ModePerm); makeDirErr != nil {
+		log.Fatalf("Error creating directory: %v\n", makeDirErr)
+	}
 
+	if configFile == "" {
+		// Nothing found anywhere sidekick has ever looked - this is a brand
+		// new install, so it's safe to create an empty canonical file.
+		configFile = canonicalFile
+		if _, statErr := os.Stat(configFile); os.IsNotExist(statErr) {
+			file, fileCreateErr := os.Create(configFile)
+			if fileCreateErr != nil {
+				log.Fatalf("Error creating configFile: %v\n", fileCreateErr)
+			}
+			file.Close()
+		}
+	} else if !explicitConfig && configFile != canonicalFile {
+		// Found a legacy config - migrate it to the canonical path instead
+		// of leaving it behind or, worse, overwriting it in place.
+		if renameErr := os.Rename(configFile, canonicalFile); renameErr != nil {
+			log.Fatalf("Error migrating legacy config from %s to %s: %v\n", configFile, canonicalFile, renameErr)
+		}
+		log.Printf("Migrated sidekick config from %s to %s\n", configFile, canonicalFile)
+		configFile = canonicalFile
 	}
-	file.Close()
 
+	viper.SetConfigFile(configFile)
+	viper.SetConfigType("yaml")
 }
 
+// configFlag holds the value of the global --config flag once cobra parses
+// it, for initConfig (which runs via cobra.OnInitialize, before Run) to read.
+var configFlag string
+
 func init() {
 	rootCmd.AddCommand(initCmd)
 	cobra.OnInitialize(initConfig)
 
-	initCmd.Flags().StringP("server", "s", "", "Set the IP address of your Server")
-	viper.BindPFlag("serverAddress", initCmd.Flags().Lookup("server"))
+	rootCmd.PersistentFlags().StringVar(&configFlag, "config", "", "Path to the sidekick config file (defaults to discovering one automatically)")
+
+	initCmd.Flags().StringP("profile", "p", config.DefaultProfile, "Name of the server profile to set up (for multi-VPS setups)")
 
+	initCmd.Flags().StringP("server", "s", "", "Set the IP address of your Server")
 	initCmd.Flags().StringP("email", "e", "", "An email address to be used for SSL certs")
-	viper.BindPFlag("certEmail", initCmd.Flags().Lookup("email"))
+
+	initCmd.Flags().Bool("non-interactive", false, "Fail instead of prompting when a required value is missing (for CI pipelines)")
+	initCmd.Flags().String("ssh-user", "", "SSH user to bootstrap with when --bootstrap-user is not given (falls back to 'root' only with --allow-root-login)")
+	initCmd.Flags().String("ssh-key", "", "Path to the SSH private key used to log in to the VPS")
+	initCmd.Flags().Int("ssh-port", 22, "SSH port to connect to the VPS on")
+	initCmd.Flags().String("bootstrap-user", "", "Non-root sudo user pre-provisioned on the VPS to bootstrap sidekick with")
+	initCmd.Flags().String("bootstrap-key", "", "Path to the SSH private key for --bootstrap-user (defaults to --ssh-key)")
+	initCmd.Flags().Bool("allow-root-login", false, "Allow falling back to the 'root' user when no --bootstrap-user is given")
+
+	initCmd.Flags().String("dns-provider", "", "DNS provider for DNS-01 ACME challenges (cloudflare, route53, digitalocean, hetzner) - omit for HTTP-01")
+	initCmd.Flags().String("domains", "", "Comma/semicolon-separated main domain plus SANs for the TLS cert (required with --dns-provider for wildcard certs)")
+
+	initCmd.Flags().Int("ca-bits", 4096, "RSA key size for the internal CA")
+	initCmd.Flags().Bool("ca-ed25519", false, "Generate an Ed25519 internal CA instead of RSA")
+	initCmd.Flags().Int("ca-years", 10, "Validity, in years, for the internal CA root certificate")
+	initCmd.Flags().String("ca-org", "sidekick", "Organization name for the internal CA's subject")
+	initCmd.Flags().String("ca-country", "US", "Country code for the internal CA's subject")
 }
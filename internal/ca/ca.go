@@ -0,0 +1,206 @@
+/*
+Copyright © 2024 Mahmoud Mosua <m.mousa@hey.com>
+
+Licensed under the GNU GPL License, Version 3.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+https://www.gnu.org/licenses/gpl-3.0.en.html
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+// Package ca provisions a small internal certificate authority so sidekick
+// can give the VPS and the local CLI a durable mTLS identity, instead of
+// relying on SSH agent forwarding for every authenticated request.
+package ca
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"time"
+)
+
+// Options configures how the root CA is generated.
+type Options struct {
+	// Bits is the RSA key size. Ignored when Ed25519 is true.
+	Bits int
+	// Ed25519 generates an Ed25519 key pair instead of RSA.
+	Ed25519 bool
+	// Years is how long the root CA cert is valid for.
+	Years int
+	// Org and Country are set on the CA's subject.
+	Org     string
+	Country string
+}
+
+// CA is a generated root certificate authority, PEM-encoded and ready to
+// sign leaf certificates or be written to disk.
+type CA struct {
+	CertPEM []byte
+	KeyPEM  []byte
+
+	cert *x509.Certificate
+	key  any
+}
+
+// Generate creates a new root CA according to opts.
+func Generate(opts Options) (*CA, error) {
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, fmt.Errorf("unable to generate CA serial: %w", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			Organization: []string{opts.Org},
+			Country:      []string{opts.Country},
+			CommonName:   "sidekick internal CA",
+		},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().AddDate(opts.Years, 0, 0),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	pub, priv, err := generateKeyPair(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, pub, priv)
+	if err != nil {
+		return nil, fmt.Errorf("unable to self-sign CA certificate: %w", err)
+	}
+
+	keyPEM, err := encodeKey(priv)
+	if err != nil {
+		return nil, err
+	}
+
+	cert, err := x509.ParseCertificate(certDER)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse generated CA certificate: %w", err)
+	}
+
+	return &CA{
+		CertPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}),
+		KeyPEM:  keyPEM,
+		cert:    cert,
+		key:     priv,
+	}, nil
+}
+
+// Load reconstructs a CA from a previously generated PEM-encoded certificate
+// and private key, so callers that don't keep the CA's private key locally
+// (it's only ever shipped encrypted to the VPS) can fetch it on demand and
+// issue further certs through the same IssueCert used by Generate's caller.
+func Load(certPEM, keyPEM []byte) (*CA, error) {
+	certBlock, _ := pem.Decode(certPEM)
+	if certBlock == nil {
+		return nil, fmt.Errorf("invalid CA certificate PEM")
+	}
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse CA certificate: %w", err)
+	}
+
+	keyBlock, _ := pem.Decode(keyPEM)
+	if keyBlock == nil {
+		return nil, fmt.Errorf("invalid CA private key PEM")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse CA private key: %w", err)
+	}
+
+	return &CA{CertPEM: certPEM, KeyPEM: keyPEM, cert: cert, key: key}, nil
+}
+
+// IssueCert signs a leaf certificate for commonName, valid for years, using
+// ca as the issuer. server marks the certificate for TLS server auth
+// (otherwise it's issued for client auth).
+func (ca *CA) IssueCert(commonName string, years int, server bool) (certPEM, keyPEM []byte, err error) {
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to generate certificate serial: %w", err)
+	}
+
+	extKeyUsage := x509.ExtKeyUsageClientAuth
+	if server {
+		extKeyUsage = x509.ExtKeyUsageServerAuth
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().AddDate(years, 0, 0),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{extKeyUsage},
+	}
+	if server {
+		if ip := net.ParseIP(commonName); ip != nil {
+			template.IPAddresses = []net.IP{ip}
+		} else {
+			template.DNSNames = []string{commonName}
+		}
+	}
+
+	pub, priv, genErr := generateKeyPair(Options{Bits: 2048})
+	if genErr != nil {
+		return nil, nil, genErr
+	}
+
+	certDER, err := x509.CreateCertificate(rand.Reader, template, ca.cert, pub, ca.key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to issue certificate for %s: %w", commonName, err)
+	}
+
+	keyPEM, err = encodeKey(priv)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}), keyPEM, nil
+}
+
+func generateKeyPair(opts Options) (pub, priv any, err error) {
+	if opts.Ed25519 {
+		pub, priv, err = ed25519.GenerateKey(rand.Reader)
+		return
+	}
+	bits := opts.Bits
+	if bits == 0 {
+		bits = 4096
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to generate %d-bit RSA key: %w", bits, err)
+	}
+	return &rsaKey.PublicKey, rsaKey, nil
+}
+
+func encodeKey(priv any) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal private key: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+func randomSerial() (*big.Int, error) {
+	limit := new(big.Int).Lsh(big.NewInt(1), 128)
+	return rand.Int(rand.Reader, limit)
+}
@@ -0,0 +1,117 @@
+/*
+Copyright © 2024 Mahmoud Mosua <m.mousa@hey.com>
+
+Licensed under the GNU GPL License, Version 3.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+https://www.gnu.org/licenses/gpl-3.0.en.html
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package ca
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+)
+
+func TestGenerateIssueCertRoundTrip(t *testing.T) {
+	rootCA, err := Generate(Options{Bits: 2048, Years: 10, Org: "sidekick", Country: "US"})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	rootBlock, _ := pem.Decode(rootCA.CertPEM)
+	if rootBlock == nil {
+		t.Fatal("Generate did not return a valid PEM-encoded root certificate")
+	}
+	rootCert, err := x509.ParseCertificate(rootBlock.Bytes)
+	if err != nil {
+		t.Fatalf("unable to parse root certificate: %v", err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(rootCert)
+
+	cases := []struct {
+		name         string
+		server       bool
+		wantKeyUsage x509.ExtKeyUsage
+	}{
+		{name: "vps.example.com", server: true, wantKeyUsage: x509.ExtKeyUsageServerAuth},
+		{name: "203.0.113.10", server: true, wantKeyUsage: x509.ExtKeyUsageServerAuth},
+		{name: "sidekick-cli", server: false, wantKeyUsage: x509.ExtKeyUsageClientAuth},
+	}
+
+	for _, tc := range cases {
+		certPEM, _, err := rootCA.IssueCert(tc.name, 1, tc.server)
+		if err != nil {
+			t.Fatalf("IssueCert(%q): %v", tc.name, err)
+		}
+
+		leafBlock, _ := pem.Decode(certPEM)
+		if leafBlock == nil {
+			t.Fatalf("IssueCert(%q) did not return a valid PEM-encoded certificate", tc.name)
+		}
+		leaf, err := x509.ParseCertificate(leafBlock.Bytes)
+		if err != nil {
+			t.Fatalf("unable to parse issued certificate for %q: %v", tc.name, err)
+		}
+
+		opts := x509.VerifyOptions{Roots: pool, KeyUsages: []x509.ExtKeyUsage{tc.wantKeyUsage}}
+		if _, err := leaf.Verify(opts); err != nil {
+			t.Fatalf("issued certificate for %q does not verify against the root: %v", tc.name, err)
+		}
+		if tc.server {
+			if err := leaf.VerifyHostname(tc.name); err != nil {
+				t.Errorf("certificate for %q does not cover its own name as a SAN: %v", tc.name, err)
+			}
+		}
+
+		found := false
+		for _, usage := range leaf.ExtKeyUsage {
+			if usage == tc.wantKeyUsage {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("certificate for %q has ExtKeyUsage %v, want it to include %v", tc.name, leaf.ExtKeyUsage, tc.wantKeyUsage)
+		}
+	}
+}
+
+func TestLoadRoundTrip(t *testing.T) {
+	rootCA, err := Generate(Options{Bits: 2048, Years: 10, Org: "sidekick", Country: "US"})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	loaded, err := Load(rootCA.CertPEM, rootCA.KeyPEM)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	certPEM, _, err := loaded.IssueCert("loaded-client", 1, false)
+	if err != nil {
+		t.Fatalf("IssueCert on a loaded CA: %v", err)
+	}
+
+	rootBlock, _ := pem.Decode(rootCA.CertPEM)
+	rootCert, _ := x509.ParseCertificate(rootBlock.Bytes)
+	pool := x509.NewCertPool()
+	pool.AddCert(rootCert)
+
+	leafBlock, _ := pem.Decode(certPEM)
+	leaf, err := x509.ParseCertificate(leafBlock.Bytes)
+	if err != nil {
+		t.Fatalf("unable to parse certificate issued by a loaded CA: %v", err)
+	}
+	if _, err := leaf.Verify(x509.VerifyOptions{Roots: pool, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}}); err != nil {
+		t.Fatalf("certificate issued by a loaded CA does not verify against the root: %v", err)
+	}
+}
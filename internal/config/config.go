@@ -0,0 +1,193 @@
+/*
+Copyright © 2024 Mahmoud Mosua <m.mousa@hey.com>
+
+Licensed under the GNU GPL License, Version 3.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+https://www.gnu.org/licenses/gpl-3.0.en.html
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+// Package config is the typed, profile-aware replacement for scattering
+// viper.GetString/Set calls across the cmd package. A user can manage more
+// than one VPS with sidekick, so every setting lives under a named profile
+// (e.g. "prod", "staging") rather than at the top level of the config file.
+package config
+
+import (
+	"fmt"
+
+	"github.com/spf13/viper"
+)
+
+// DefaultProfile is used when the user doesn't pass --profile.
+const DefaultProfile = "default"
+
+// Configuration is the full on-disk shape of ~/.config/sidekick/default.yaml.
+type Configuration struct {
+	Servers map[string]ServerProfile `mapstructure:"servers"`
+}
+
+// ServerProfile holds everything sidekick needs to talk to a single VPS.
+type ServerProfile struct {
+	Address         string          `mapstructure:"address"`
+	CertEmail       string          `mapstructure:"certEmail"`
+	PublicKey       string          `mapstructure:"publicKey"`
+	CompletedStages map[string]bool `mapstructure:"completedStages"`
+	DNSProvider     string          `mapstructure:"dnsProvider"`
+	Domains         []string        `mapstructure:"domains"`
+	CARootCert      string          `mapstructure:"caRootCert"`
+	ClientCert      string          `mapstructure:"clientCert"`
+	ClientKey       string          `mapstructure:"clientKey"`
+}
+
+// ConfigState mirrors viper's in-memory config and is the only thing callers
+// should mutate. Every setter also calls viper.Set so a later
+// viper.WriteConfig picks up the change without the caller having to know
+// viper is involved at all.
+type ConfigState struct {
+	config Configuration
+}
+
+// Load reads the current viper state into a ConfigState ready for use.
+func Load() (*ConfigState, error) {
+	state := &ConfigState{}
+	if err := viper.Unmarshal(&state.config); err != nil {
+		return nil, fmt.Errorf("unable to parse sidekick config: %w", err)
+	}
+	if state.config.Servers == nil {
+		state.config.Servers = map[string]ServerProfile{}
+	}
+	return state, nil
+}
+
+// Profiles returns the names of every profile currently on disk.
+func (s *ConfigState) Profiles() []string {
+	names := make([]string, 0, len(s.config.Servers))
+	for name := range s.config.Servers {
+		names = append(names, name)
+	}
+	return names
+}
+
+func (s *ConfigState) profile(name string) ServerProfile {
+	return s.config.Servers[name]
+}
+
+func (s *ConfigState) setProfile(name string, profile ServerProfile) {
+	if s.config.Servers == nil {
+		s.config.Servers = map[string]ServerProfile{}
+	}
+	s.config.Servers[name] = profile
+	viper.Set("servers", s.config.Servers)
+}
+
+// GetServerAddress returns the VPS IPv4 address stored for profile.
+func (s *ConfigState) GetServerAddress(profile string) string {
+	return s.profile(profile).Address
+}
+
+// SetServerAddress stores the VPS IPv4 address for profile.
+func (s *ConfigState) SetServerAddress(profile, address string) {
+	p := s.profile(profile)
+	p.Address = address
+	s.setProfile(profile, p)
+}
+
+// GetCertEmail returns the ACME contact email stored for profile.
+func (s *ConfigState) GetCertEmail(profile string) string {
+	return s.profile(profile).CertEmail
+}
+
+// SetCertEmail stores the ACME contact email for profile.
+func (s *ConfigState) SetCertEmail(profile, email string) {
+	p := s.profile(profile)
+	p.CertEmail = email
+	s.setProfile(profile, p)
+}
+
+// IsStageComplete reports whether stage has already finished successfully
+// for profile, so init can skip it on a re-run after a transient failure.
+func (s *ConfigState) IsStageComplete(profile, stage string) bool {
+	return s.profile(profile).CompletedStages[stage]
+}
+
+// SetStageComplete marks stage as finished for profile.
+func (s *ConfigState) SetStageComplete(profile, stage string) {
+	p := s.profile(profile)
+	if p.CompletedStages == nil {
+		p.CompletedStages = map[string]bool{}
+	}
+	p.CompletedStages[stage] = true
+	s.setProfile(profile, p)
+}
+
+// GetDNSProvider returns the DNS-01 ACME provider configured for profile,
+// or "" if the profile uses the default HTTP-01 challenge.
+func (s *ConfigState) GetDNSProvider(profile string) string {
+	return s.profile(profile).DNSProvider
+}
+
+// SetDNSProvider stores the DNS-01 ACME provider for profile.
+func (s *ConfigState) SetDNSProvider(profile, provider string) {
+	p := s.profile(profile)
+	p.DNSProvider = provider
+	s.setProfile(profile, p)
+}
+
+// GetDomains returns the main domain plus any SANs configured for profile's
+// certificate.
+func (s *ConfigState) GetDomains(profile string) []string {
+	return s.profile(profile).Domains
+}
+
+// SetDomains stores the main domain plus any SANs for profile's certificate.
+func (s *ConfigState) SetDomains(profile string, domains []string) {
+	p := s.profile(profile)
+	p.Domains = domains
+	s.setProfile(profile, p)
+}
+
+// GetCARootCert returns the PEM-encoded root CA certificate for profile.
+func (s *ConfigState) GetCARootCert(profile string) string {
+	return s.profile(profile).CARootCert
+}
+
+// SetCARootCert stores the PEM-encoded root CA certificate for profile.
+func (s *ConfigState) SetCARootCert(profile, certPEM string) {
+	p := s.profile(profile)
+	p.CARootCert = certPEM
+	s.setProfile(profile, p)
+}
+
+// GetClientCert returns the PEM-encoded client certificate issued for the
+// local sidekick CLI to authenticate against profile's VPS.
+func (s *ConfigState) GetClientCert(profile string) (certPEM, keyPEM string) {
+	p := s.profile(profile)
+	return p.ClientCert, p.ClientKey
+}
+
+// SetClientCert stores the PEM-encoded client certificate and key issued
+// for the local sidekick CLI to authenticate against profile's VPS.
+func (s *ConfigState) SetClientCert(profile, certPEM, keyPEM string) {
+	p := s.profile(profile)
+	p.ClientCert = certPEM
+	p.ClientKey = keyPEM
+	s.setProfile(profile, p)
+}
+
+// GetPublicKey returns the age public key generated on the VPS for profile.
+func (s *ConfigState) GetPublicKey(profile string) string {
+	return s.profile(profile).PublicKey
+}
+
+// SetPublicKey stores the age public key generated on the VPS for profile.
+func (s *ConfigState) SetPublicKey(profile, publicKey string) {
+	p := s.profile(profile)
+	p.PublicKey = publicKey
+	s.setProfile(profile, p)
+}
@@ -0,0 +1,87 @@
+/*
+Copyright © 2024 Mahmoud Mosua <m.mousa@hey.com>
+
+Licensed under the GNU GPL License, Version 3.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+https://www.gnu.org/licenses/gpl-3.0.en.html
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package utils
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/pterm/pterm"
+	"golang.org/x/crypto/ssh"
+)
+
+// Login opens an SSH connection to address as user. keyPath defaults to
+// ~/.ssh/sidekick when empty, and port defaults to 22 when zero.
+func Login(address, user, keyPath string, port int) (*ssh.Client, error) {
+	if port == 0 {
+		port = 22
+	}
+	if keyPath == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		keyPath = fmt.Sprintf("%s/.ssh/sidekick", home)
+	}
+
+	key, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read SSH key %s: %w", keyPath, err)
+	}
+	signer, err := ssh.ParsePrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse SSH key %s: %w", keyPath, err)
+	}
+
+	clientConfig := &ssh.ClientConfig{
+		User:            user,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         10 * time.Second,
+	}
+
+	return ssh.Dial("tcp", net.JoinHostPort(address, fmt.Sprintf("%d", port)), clientConfig)
+}
+
+// RunCommand runs cmd on client over a fresh SSH session and streams the
+// combined stdout/stderr back on the returned channel once it finishes.
+func RunCommand(client *ssh.Client, cmd string) (<-chan string, error) {
+	session, err := client.NewSession()
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan string, 1)
+	go func() {
+		defer session.Close()
+		output, _ := session.CombinedOutput(cmd)
+		ch <- string(output)
+	}()
+
+	return ch, nil
+}
+
+// IsValidIPAddress reports whether ip parses as a valid IPv4/IPv6 address.
+func IsValidIPAddress(ip string) bool {
+	return net.ParseIP(ip) != nil
+}
+
+// GetSpinner returns a fresh copy of pterm's default spinner for a caller to
+// customize with WithWriter/Start.
+func GetSpinner() pterm.SpinnerPrinter {
+	return pterm.DefaultSpinner
+}
@@ -0,0 +1,224 @@
+/*
+Copyright © 2024 Mahmoud Mosua <m.mousa@hey.com>
+
+Licensed under the GNU GPL License, Version 3.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+https://www.gnu.org/licenses/gpl-3.0.en.html
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package utils
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Stage is a named set of commands run once against a freshly-dialed SSH
+// client during `sidekick init`. CheckFn probes the remote box directly
+// (rather than trusting local state alone) so RunStage can short-circuit a
+// stage that's already done - e.g. after the local profile was deleted, or
+// the VPS was mutated out of band.
+type Stage struct {
+	Commands              []string
+	SpinnerSuccessMessage string
+	SpinnerFailMessage    string
+	CheckFn               func(client *ssh.Client) bool
+}
+
+// RunStage runs every command in stage sequentially over client, skipping
+// the work entirely when stage.CheckFn reports the stage is already done.
+func RunStage(client *ssh.Client, stage Stage) error {
+	if stage.CheckFn != nil && stage.CheckFn(client) {
+		return nil
+	}
+	for _, command := range stage.Commands {
+		ch, err := RunCommand(client, command)
+		if err != nil {
+			return fmt.Errorf("running %q: %w", command, err)
+		}
+		if output := <-ch; strings.Contains(output, "E: ") {
+			return fmt.Errorf("command %q failed: %s", command, output)
+		}
+	}
+	return nil
+}
+
+// probe runs cmd on client and reports whether it produced any output,
+// which is enough to tell apart "id sidekick" on a box that has the user
+// from one that doesn't (the command errors to stderr instead).
+func probe(client *ssh.Client, cmd string) bool {
+	// RunCommand merges stderr into its output, so discard it here.
+	ch, err := RunCommand(client, cmd+" 2>/dev/null")
+	if err != nil {
+		return false
+	}
+	return strings.TrimSpace(<-ch) != ""
+}
+
+// RecordRemoteStage appends stage to the VPS's own record of completed
+// stages at ~/.sidekick/state.yaml, so a profile that's lost locally (or
+// reused against a different local sidekick install) can still tell which
+// stages the box has already been through. See RemoteStageComplete.
+func RecordRemoteStage(client *ssh.Client, stage string) error {
+	ch, err := RunCommand(client, fmt.Sprintf(
+		`mkdir -p $HOME/.sidekick && grep -q '^%s: true$' $HOME/.sidekick/state.yaml 2>/dev/null || echo '%s: true' >> $HOME/.sidekick/state.yaml`,
+		stage, stage,
+	))
+	if err != nil {
+		return err
+	}
+	<-ch
+	return nil
+}
+
+// RemoteStageComplete reports whether stage is marked done in the VPS's own
+// ~/.sidekick/state.yaml, written by RecordRemoteStage. Callers OR this into
+// their local configState.IsStageComplete check so a profile that's lost
+// locally (or reused against a different local sidekick install) still skips
+// stages the box has already been through.
+func RemoteStageComplete(client *ssh.Client, stage string) bool {
+	return probe(client, fmt.Sprintf(
+		`grep -q '^%s: true$' $HOME/.sidekick/state.yaml 2>/dev/null && echo yes`,
+		stage,
+	))
+}
+
+// GetUsersetupStage returns the Stage that creates the sidekick user.
+// bootstrapUser is the user RunStage will connect as; when it isn't "root"
+// its commands are prefixed with sudo so a non-root pre-provisioned sudo
+// user can bootstrap the box too.
+func GetUsersetupStage(bootstrapUser string) Stage {
+	commands := []string{
+		"adduser --disabled-password --gecos '' sidekick",
+		"usermod -aG sudo sidekick",
+		"mkdir -p /home/sidekick/.ssh",
+		"cp $HOME/.ssh/authorized_keys /home/sidekick/.ssh/authorized_keys",
+		"chown -R sidekick:sidekick /home/sidekick/.ssh",
+	}
+	if bootstrapUser != "root" {
+		for i, command := range commands {
+			commands[i] = "sudo " + command
+		}
+	}
+
+	return Stage{
+		Commands:              commands,
+		SpinnerSuccessMessage: "Added user Sidekick",
+		SpinnerFailMessage:    "Failed to add user Sidekick",
+		CheckFn: func(client *ssh.Client) bool {
+			return probe(client, "id sidekick")
+		},
+	}
+}
+
+var SetupStage = Stage{
+	Commands: []string{
+		"apt-get update -y",
+		"apt-get install -y age sops curl ufw",
+		"ufw allow OpenSSH && ufw allow 80 && ufw allow 443 && ufw --force enable",
+	},
+	SpinnerSuccessMessage: "VPS set up",
+	SpinnerFailMessage:    "Failed to set up VPS",
+}
+
+var DockerStage = Stage{
+	Commands: []string{
+		"curl -fsSL https://get.docker.com | sh",
+		"usermod -aG docker sidekick",
+	},
+	SpinnerSuccessMessage: "Docker set up",
+	SpinnerFailMessage:    "Failed to set up Docker",
+	CheckFn: func(client *ssh.Client) bool {
+		return probe(client, "docker --version")
+	},
+}
+
+// traefikMTLSDynamicConfig is Traefik's file-provider dynamic config that
+// requires a client certificate signed by the internal CA (shipped to
+// $HOME/.sidekick/ca/ca.crt by provisionCA) for the API/dashboard router.
+const traefikMTLSDynamicConfig = `tls:
+  options:
+    mtls:
+      clientAuth:
+        caFiles:
+          - /ca/ca.crt
+        clientAuthType: RequireAndVerifyClientCert
+http:
+  routers:
+    api:
+      rule: "PathPrefix(` + "`/api`" + `) || PathPrefix(` + "`/dashboard`" + `)"
+      service: api@internal
+      entrypoints:
+        - traefik
+      tls:
+        options: mtls@file
+`
+
+// GetTraefikStage renders Traefik's static+dynamic config for certEmail and
+// returns the Stage that installs and starts it. When dnsProvider is set,
+// the ACME resolver uses a DNS-01 challenge against domains (enabling
+// wildcard certs) instead of the default HTTP-01 challenge on ports 80/443.
+// When mtls is true, the internal CA provisioned by provisionCA is mounted
+// into the container and the API/dashboard router is required to present a
+// client certificate signed by it.
+func GetTraefikStage(certEmail, dnsProvider string, domains []string, mtls bool) Stage {
+	acmeResolver := fmt.Sprintf("--certificatesresolvers.le.acme.email=%s", certEmail)
+	if dnsProvider != "" {
+		acmeResolver += fmt.Sprintf(
+			" --certificatesresolvers.le.acme.dnschallenge=true --certificatesresolvers.le.acme.dnschallenge.provider=%s",
+			dnsProvider,
+		)
+		if len(domains) > 0 {
+			acmeResolver += fmt.Sprintf(" --certificatesresolvers.le.acme.domains[0].main=%s", domains[0])
+			if len(domains) > 1 {
+				acmeResolver += fmt.Sprintf(
+					" --certificatesresolvers.le.acme.domains[0].sans=%s",
+					strings.Join(domains[1:], ","),
+				)
+			}
+		}
+	}
+
+	commands := []string{"mkdir -p $HOME/.sidekick/traefik"}
+
+	runArgs := "--providers.docker=true " + acmeResolver
+	volumes := "-v /var/run/docker.sock:/var/run/docker.sock:ro -v $HOME/.sidekick/traefik:/letsencrypt"
+	ports := "-p 80:80 -p 443:443"
+	envFile := ""
+	if dnsProvider != "" {
+		commands = append(commands,
+			"sops decrypt $HOME/.sidekick/dns-credentials.env.enc > $HOME/.sidekick/dns-credentials.env")
+		envFile = " --env-file $HOME/.sidekick/dns-credentials.env"
+	}
+	if mtls {
+		commands = append(commands, fmt.Sprintf(
+			"cat <<'EOF' > $HOME/.sidekick/traefik/dynamic.yaml\n%sEOF",
+			traefikMTLSDynamicConfig,
+		))
+		volumes += " -v $HOME/.sidekick/traefik/dynamic.yaml:/etc/traefik/dynamic.yaml:ro -v $HOME/.sidekick/ca/ca.crt:/ca/ca.crt:ro"
+		ports += " -p 8080:8080"
+		runArgs += " --api.dashboard=true --entrypoints.traefik.address=:8080 --providers.file.filename=/etc/traefik/dynamic.yaml"
+	}
+
+	commands = append(commands, fmt.Sprintf(
+		"docker run -d --name traefik --restart unless-stopped %s %s%s traefik:v3.0 %s",
+		ports, volumes, envFile, runArgs,
+	))
+
+	return Stage{
+		Commands:              commands,
+		SpinnerSuccessMessage: "Traefik set up",
+		SpinnerFailMessage:    "Failed to set up Traefik",
+		CheckFn: func(client *ssh.Client) bool {
+			return probe(client, "docker ps --filter name=traefik --filter status=running -q")
+		},
+	}
+}